@@ -1,24 +1,116 @@
 package nats
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ErrRequestTimeout is returned by Client.Request when no reply arrives
+// within the given timeout.
+var ErrRequestTimeout = errors.New("nats: request timed out")
+
+// ErrNoConnection is returned when an operation requiring a live connection
+// is attempted while the client has none to offer, e.g. because Run has
+// already returned.
+var ErrNoConnection = errors.New("nats: no connection available")
+
+// ErrHeartbeatFailure is returned by runConnection when a connection is
+// stopped internally after reaching MaxMissedHeartbeats, so that its return
+// value is not mistaken for a deliberate Stop/ctx-cancellation. RunContext
+// itself decides whether to reconnect from its context alone.
+var ErrHeartbeatFailure = errors.New("nats: connection stopped after missed heartbeats")
+
+// ErrHandlerQueueOverflow is passed to Client.HandlerOverflow when an
+// asynchronous subscription's handler queue is full and a message had to be
+// dropped rather than block the connection's read loop.
+var ErrHandlerQueueOverflow = errors.New("nats: subscription handler queue overflowed")
+
+// defaultHandlerQueueSize is used by SetHandler subscriptions that have not
+// called SetHandlerQueueSize.
+const defaultHandlerQueueSize = 64
+
+// SubscriptionHandler is invoked, once per message and in order, for
+// subscriptions that opt into asynchronous delivery via SetHandler.
+type SubscriptionHandler func(*readMessage)
+
+// inboxPrefix is prepended to every subject generated by NewInbox, matching
+// the convention used throughout the NATS ecosystem.
+const inboxPrefix = "_INBOX."
+
+// NewInbox returns a unique, hard-to-guess subject suitable for use as the
+// reply-to subject of a request. The entropy is drawn from crypto/rand and
+// base32-encoded, giving 22+ bytes of randomness per inbox.
+func NewInbox() string {
+	var b [22]byte
+
+	if _, e := rand.Read(b[:]); e != nil {
+		panic("nats: failed to read random bytes: " + e.Error())
+	}
+
+	return inboxPrefix + base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b[:])
+}
+
 type Subscription struct {
 	sr *subscriptionRegistry
 
-	sid      uint
-	frozen   bool
-	maximum  uint
-	received uint
+	sid       uint
+	frozen    bool
+	closed    bool
+	maximum   uint
+	received  uint
+	delivered uint
+	dropped   uint
+
+	// mu guards received, delivered and dropped, which deliver() mutates
+	// from the connection's read-loop goroutine concurrently with readers
+	// such as Delivered, Dropped and the registry's Subscriptions/resubscribe.
+	mu sync.Mutex
 
 	subject string
 	queue   string
 
+	handler          SubscriptionHandler
+	handlerQueueSize uint
+	handlerQueue     chan *readMessage
+
 	Inbox chan *readMessage
 }
 
+// SubscriptionInfo is a point-in-time snapshot of a Subscription, as
+// returned by Client.Subscriptions.
+type SubscriptionInfo struct {
+	Sid      uint
+	Subject  string
+	Queue    string
+	Received uint
+	Maximum  uint
+}
+
+// Delivered returns the number of messages successfully handed to this
+// subscription's Inbox or handler.
+func (s *Subscription) Delivered() uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delivered
+}
+
+// Dropped returns the number of messages discarded because this
+// subscription's handler queue was full. It is always zero unless
+// SetHandler was used.
+func (s *Subscription) Dropped() uint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.dropped
+}
+
 func (s *Subscription) freeze() {
 	if s.frozen {
 		panic("subscription is frozen")
@@ -51,6 +143,38 @@ func (s *Subscription) SetMaximum(v uint) {
 	s.maximum = v
 }
 
+// SetHandler switches the subscription to asynchronous delivery: instead of
+// messages being sent on Inbox, each message is passed to h from a dedicated
+// per-subscription goroutine, so a slow or blocking handler cannot starve the
+// connection's read loop. Inbox and SetHandler are mutually exclusive once
+// the subscription is frozen.
+func (s *Subscription) SetHandler(h SubscriptionHandler) {
+	if s.frozen {
+		panic("subscription is frozen")
+	}
+
+	s.handler = h
+}
+
+// SetHandlerQueueSize overrides the capacity of the queue feeding the
+// per-subscription handler goroutine. It has no effect unless SetHandler has
+// also been called. The default is defaultHandlerQueueSize.
+func (s *Subscription) SetHandlerQueueSize(v uint) {
+	if s.frozen {
+		panic("subscription is frozen")
+	}
+
+	s.handlerQueueSize = v
+}
+
+// runHandler drains handlerQueue on its own goroutine, calling the handler
+// for each message in order, until the queue is closed on unsubscribe.
+func (s *Subscription) runHandler() {
+	for m := range s.handlerQueue {
+		s.handler(m)
+	}
+}
+
 func (s *Subscription) writeSubscribe() writeObject {
 	var o = new(writeSubscribe)
 
@@ -82,11 +206,41 @@ func (s *Subscription) Unsubscribe() {
 }
 
 func (s *Subscription) deliver(m *readMessage) {
+	s.mu.Lock()
 	s.received++
-	s.Inbox <- m
+	s.mu.Unlock()
+
+	if s.handler != nil {
+		select {
+		case s.handlerQueue <- m:
+			s.mu.Lock()
+			s.delivered++
+			s.mu.Unlock()
+		default:
+			// The handler goroutine can't keep up; drop the message rather
+			// than block the connection's read loop.
+			s.mu.Lock()
+			s.dropped++
+			s.mu.Unlock()
+
+			if h := s.sr.Client.HandlerOverflow; h != nil {
+				h(ErrHandlerQueueOverflow)
+			}
+		}
+	} else {
+		s.Inbox <- m
+
+		s.mu.Lock()
+		s.delivered++
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	var done = s.maximum > 0 && s.received >= s.maximum
+	s.mu.Unlock()
 
 	// Unsubscribe if the maximum number of messages has been received
-	if s.maximum > 0 && s.received >= s.maximum {
+	if done {
 		s.Unsubscribe()
 	}
 }
@@ -114,7 +268,16 @@ func (sr *subscriptionRegistry) teardown() {
 	defer sr.Unlock()
 
 	for _, s := range sr.m {
-		close(s.Inbox)
+		// Mark closed under the same lock Unsubscribe uses, so a concurrent
+		// Unsubscribe call for one of these subscriptions becomes a no-op
+		// instead of double-closing the channel below.
+		s.closed = true
+
+		if s.handler != nil {
+			close(s.handlerQueue)
+		} else {
+			close(s.Inbox)
+		}
 	}
 
 	sr.emptyMap()
@@ -144,6 +307,16 @@ func (sr *subscriptionRegistry) Subscribe(s *Subscription) {
 	sr.m[s.sid] = s
 	s.freeze()
 
+	if s.handler != nil {
+		size := s.handlerQueueSize
+		if size == 0 {
+			size = defaultHandlerQueueSize
+		}
+
+		s.handlerQueue = make(chan *readMessage, size)
+		go s.runHandler()
+	}
+
 	sr.Unlock()
 
 	sr.Client.Write(s.writeSubscribe())
@@ -155,20 +328,58 @@ func (sr *subscriptionRegistry) Subscribe(s *Subscription) {
 	return
 }
 
+// Unsubscribe is idempotent: a Subscription may be unsubscribed concurrently
+// from more than one place (e.g. deliver() reaching its maximum at the same
+// instant a caller times out waiting on it), and only the first call may
+// close its channel.
 func (sr *subscriptionRegistry) Unsubscribe(s *Subscription) {
 	sr.Lock()
 
+	if s.closed {
+		sr.Unlock()
+		return
+	}
+
+	s.closed = true
 	delete(sr.m, s.sid)
 
 	// Since this subscription is now removed from the registry, it will no
-	// longer receive messages and the inbox can be closed
-	close(s.Inbox)
+	// longer receive messages and the inbox (or handler queue) can be closed
+	if s.handler != nil {
+		close(s.handlerQueue)
+	} else {
+		close(s.Inbox)
+	}
 
 	sr.Unlock()
 
 	sr.Client.Write(s.writeUnsubscribe(false))
+}
 
-	return
+// resubscribe re-emits a writeSubscribe (and, for bounded subscriptions, a
+// writeUnsubscribe carrying the remaining message count) for every live
+// subscription onto c. It is called for every new Connection so that
+// subscriptions survive reconnects instead of silently going stale.
+func (sr *subscriptionRegistry) resubscribe(c *Connection) {
+	sr.Lock()
+	defer sr.Unlock()
+
+	for _, s := range sr.m {
+		c.Write(s.writeSubscribe())
+
+		if s.maximum > 0 {
+			s.mu.Lock()
+			var remaining = s.maximum - s.received
+			s.mu.Unlock()
+
+			var o = new(writeUnsubscribe)
+
+			o.Sid = s.sid
+			o.Maximum = remaining
+
+			c.Write(o)
+		}
+	}
 }
 
 func (sr *subscriptionRegistry) Deliver(m *readMessage) {
@@ -182,6 +393,32 @@ func (sr *subscriptionRegistry) Deliver(m *readMessage) {
 	if ok {
 		s.deliver(m)
 	}
+
+	sr.Client.addBytesIn(len(m.Message))
+}
+
+// Subscriptions returns a snapshot of every currently active subscription.
+func (sr *subscriptionRegistry) Subscriptions() []SubscriptionInfo {
+	sr.Lock()
+	defer sr.Unlock()
+
+	var infos = make([]SubscriptionInfo, 0, len(sr.m))
+
+	for _, s := range sr.m {
+		s.mu.Lock()
+		var received = s.received
+		s.mu.Unlock()
+
+		infos = append(infos, SubscriptionInfo{
+			Sid:      s.sid,
+			Subject:  s.subject,
+			Queue:    s.queue,
+			Received: received,
+			Maximum:  s.maximum,
+		})
+	}
+
+	return infos
 }
 
 type Client struct {
@@ -189,8 +426,81 @@ type Client struct {
 
 	cc chan *Connection
 
-	// Notify running client to stop
-	sc chan bool
+	// runMu guards cancel, which stops whichever RunContext call is
+	// currently active, if any.
+	runMu  sync.Mutex
+	cancel context.CancelFunc
+
+	// HandlerOverflow, when set, is called whenever an asynchronous
+	// (SetHandler) subscription's queue is full and a message had to be
+	// dropped to keep the connection's read loop unblocked.
+	HandlerOverflow func(error)
+
+	// ConnStatus, if set by the caller before Run starts, receives the new
+	// Connection every time one is established, including the first one and
+	// every reconnect. Sends are non-blocking, so a caller must keep it
+	// drained (or buffered) to see every event.
+	ConnStatus chan *Connection
+
+	// HeartbeatInterval, when non-zero, enables a background heartbeat on
+	// each Connection: every interval a PING is sent and the time until the
+	// matching PONG is recorded via LastRTT. Zero disables heartbeating.
+	HeartbeatInterval time.Duration
+
+	// MaxMissedHeartbeats is the number of consecutive unanswered heartbeats
+	// at which the connection is considered dead and stopped, forcing the
+	// outer Run loop to reconnect. Zero disables the check.
+	MaxMissedHeartbeats uint
+
+	// HeartbeatFailure, when set, is called just before a connection is
+	// stopped due to reaching MaxMissedHeartbeats.
+	HeartbeatFailure func()
+
+	rttMu   sync.Mutex
+	lastRTT time.Duration
+
+	statsMu sync.Mutex
+	stats   ClientStats
+}
+
+// ClientStats is a point-in-time snapshot of a Client's aggregate traffic
+// counters, as returned by Client.Stats.
+type ClientStats struct {
+	BytesIn     uint64
+	BytesOut    uint64
+	MessagesIn  uint64
+	MessagesOut uint64
+	Reconnects  uint64
+}
+
+// Stats returns a snapshot of the client's aggregate bytes/messages in and
+// out, and the number of times the underlying connection has been
+// re-established.
+func (t *Client) Stats() ClientStats {
+	t.statsMu.Lock()
+	defer t.statsMu.Unlock()
+
+	return t.stats
+}
+
+func (t *Client) addBytesOut(n int) {
+	t.statsMu.Lock()
+	t.stats.BytesOut += uint64(n)
+	t.stats.MessagesOut++
+	t.statsMu.Unlock()
+}
+
+func (t *Client) addBytesIn(n int) {
+	t.statsMu.Lock()
+	t.stats.BytesIn += uint64(n)
+	t.stats.MessagesIn++
+	t.statsMu.Unlock()
+}
+
+func (t *Client) recordReconnect() {
+	t.statsMu.Lock()
+	t.stats.Reconnects++
+	t.statsMu.Unlock()
 }
 
 func NewClient() *Client {
@@ -200,25 +510,27 @@ func NewClient() *Client {
 
 	t.cc = make(chan *Connection)
 
-	t.sc = make(chan bool)
-
 	return t
 }
 
-func (t *Client) AcquireConnection() *Connection {
-	var c *Connection
-	var ok bool
+// AcquireConnection waits for a live Connection to become available,
+// returning nil if ctx is done or the client stops running before one does.
+func (t *Client) AcquireConnection(ctx context.Context) *Connection {
+	select {
+	case c, ok := <-t.cc:
+		if !ok {
+			return nil
+		}
 
-	c, ok = <-t.cc
-	if !ok {
+		return c
+
+	case <-ctx.Done():
 		return nil
 	}
-
-	return c
 }
 
 func (t *Client) Write(o writeObject) bool {
-	c := t.AcquireConnection()
+	c := t.AcquireConnection(context.Background())
 	if c == nil {
 		return false
 	}
@@ -227,7 +539,7 @@ func (t *Client) Write(o writeObject) bool {
 }
 
 func (t *Client) Ping() bool {
-	c := t.AcquireConnection()
+	c := t.AcquireConnection(context.Background())
 	if c == nil {
 		return false
 	}
@@ -235,13 +547,13 @@ func (t *Client) Ping() bool {
 	return c.Ping()
 }
 
-func (t *Client) publish(s string, m []byte, confirm bool) bool {
+func (t *Client) publish(ctx context.Context, s string, m []byte, confirm bool) bool {
 	var o = new(writePublish)
 
 	o.Subject = s
 	o.Message = m
 
-	c := t.AcquireConnection()
+	c := t.AcquireConnection(ctx)
 	if c == nil {
 		return false
 	}
@@ -251,6 +563,8 @@ func (t *Client) publish(s string, m []byte, confirm bool) bool {
 		return false
 	}
 
+	t.addBytesOut(len(m))
+
 	// Round trip to confirm the publish was received
 	if confirm {
 		return c.Ping()
@@ -260,45 +574,276 @@ func (t *Client) publish(s string, m []byte, confirm bool) bool {
 }
 
 func (t *Client) Publish(s string, m []byte) bool {
-	return t.publish(s, m, false)
+	return t.publish(context.Background(), s, m, false)
 }
 
 func (t *Client) PublishAndConfirm(s string, m []byte) bool {
-	return t.publish(s, m, true)
+	return t.publish(context.Background(), s, m, true)
+}
+
+// PublishContext is the context-aware equivalent of Publish: it gives up and
+// returns false as soon as ctx is done rather than waiting indefinitely for
+// a connection.
+func (t *Client) PublishContext(ctx context.Context, s string, m []byte) bool {
+	return t.publish(ctx, s, m, false)
 }
 
+func (t *Client) publishRequest(ctx context.Context, subject, reply string, m []byte) bool {
+	var o = new(writePublish)
+
+	o.Subject = subject
+	o.ReplyTo = reply
+	o.Message = m
+
+	c := t.AcquireConnection(ctx)
+	if c == nil {
+		return false
+	}
+
+	ok := c.Write(o)
+	if !ok {
+		return false
+	}
+
+	t.addBytesOut(len(m))
+
+	return true
+}
+
+// PublishRequest publishes m on subject, setting reply as the ReplyTo subject
+// of the message. It is useful for callers managing their own inbox, e.g. a
+// scatter/gather request expecting more than one reply.
+func (t *Client) PublishRequest(subject, reply string, m []byte) bool {
+	return t.publishRequest(context.Background(), subject, reply, m)
+}
+
+// Subscribe creates and activates a Subscription on subject.
+func (t *Client) Subscribe(subject string) *Subscription {
+	var s = t.NewSubscription(subject)
+
+	s.Subscribe()
+
+	return s
+}
+
+// QueueSubscribe creates and activates a Subscription on subject within the
+// named queue group, so that only one member of the group receives any
+// given message, providing simple load-balancing across subscribers.
+func (t *Client) QueueSubscribe(subject, queue string) *Subscription {
+	var s = t.NewSubscription(subject)
+
+	s.SetQueue(queue)
+	s.Subscribe()
+
+	return s
+}
+
+// Request publishes m on subject with a freshly generated inbox as the
+// ReplyTo subject, and waits up to timeout for a single reply. On timeout the
+// ephemeral subscription used to receive the reply is unsubscribed before
+// Request returns; when a reply arrives, it is unsubscribed by the delivery
+// path once its single message has been handed over, which may complete
+// after Request has returned.
+func (t *Client) Request(subject string, m []byte, timeout time.Duration) ([]byte, error) {
+	return t.RequestContext(context.Background(), subject, m, timeout)
+}
+
+// RequestContext is the context-aware equivalent of Request: in addition to
+// the timeout, it also gives up early if ctx is done.
+func (t *Client) RequestContext(ctx context.Context, subject string, m []byte, timeout time.Duration) ([]byte, error) {
+	var s = t.NewSubscription(NewInbox())
+	s.SetMaximum(1)
+	s.Subscribe()
+
+	if !t.publishRequest(ctx, subject, s.subject, m) {
+		s.Unsubscribe()
+
+		if e := ctx.Err(); e != nil {
+			return nil, e
+		}
+
+		return nil, ErrNoConnection
+	}
+
+	var timer = time.NewTimer(timeout)
+	defer timer.Stop()
+
+	// The reply may arrive at the same instant the timeout or ctx fires, in
+	// which case deliver() races this select to call s.Unsubscribe() for the
+	// same subscription; Unsubscribe is idempotent, so whichever case Go
+	// picks here is safe to call it again.
+	select {
+	case r, ok := <-s.Inbox:
+		if !ok {
+			return nil, ErrNoConnection
+		}
+
+		return r.Message, nil
+
+	case <-timer.C:
+		s.Unsubscribe()
+		return nil, ErrRequestTimeout
+
+	case <-ctx.Done():
+		s.Unsubscribe()
+		return nil, ctx.Err()
+	}
+}
+
+// Stop ends whichever Run/RunContext call is currently active, if any. It is
+// idempotent and safe to call before Run starts or after it has already
+// returned.
 func (t *Client) Stop() {
-	t.sc <- true
+	t.runMu.Lock()
+	cancel := t.cancel
+	t.runMu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Flush blocks until a PING round trip with the server completes on the
+// current connection, which guarantees every message written before the
+// call has reached the server.
+func (t *Client) Flush() bool {
+	return t.Ping()
+}
+
+// LastRTT returns the round trip time of the most recently acknowledged
+// heartbeat. It is zero if HeartbeatInterval is unset or no heartbeat has
+// completed yet.
+func (t *Client) LastRTT() time.Duration {
+	t.rttMu.Lock()
+	defer t.rttMu.Unlock()
+
+	return t.lastRTT
+}
+
+func (t *Client) setLastRTT(d time.Duration) {
+	t.rttMu.Lock()
+	t.lastRTT = d
+	t.rttMu.Unlock()
 }
 
-func (t *Client) runConnection(n net.Conn) error {
+// heartbeat periodically PINGs c and tracks consecutive misses, stopping c
+// once MaxMissedHeartbeats is reached so the outer Run loop reconnects
+// instead of sitting on a silently half-open socket. It exits when stop is
+// closed. failed is set before c is stopped for a missed-heartbeat reason,
+// so runConnection can tell this apart from a deliberate Stop/ctx-cancel.
+func (t *Client) heartbeat(c *Connection, stop chan struct{}, failed *int32) {
+	var ticker = time.NewTicker(t.HeartbeatInterval)
+	defer ticker.Stop()
+
+	var misses uint
+	var start time.Time
+
+	// pending is non-nil while a PING is outstanding. Receiving from a nil
+	// channel blocks forever, so the "case ok := <-pending" arm below is
+	// simply inert until a PING is in flight.
+	var pending chan bool
+
+	var fail = func() {
+		if t.HeartbeatFailure != nil {
+			t.HeartbeatFailure()
+		}
+
+		atomic.StoreInt32(failed, 1)
+		c.Stop()
+	}
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case <-ticker.C:
+			if pending != nil {
+				// The previous PING never resolved in time for this tick;
+				// count it as a miss instead of starting an overlapping
+				// Ping call, which could clobber c's single pending-pong
+				// waiter.
+				misses++
+
+				if t.MaxMissedHeartbeats > 0 && misses >= t.MaxMissedHeartbeats {
+					fail()
+					return
+				}
+
+				continue
+			}
+
+			start = time.Now()
+			pending = make(chan bool, 1)
+
+			go func(done chan bool) {
+				done <- c.Ping()
+			}(pending)
+
+		case ok := <-pending:
+			pending = nil
+
+			if ok {
+				t.setLastRTT(time.Since(start))
+				misses = 0
+				continue
+			}
+
+			misses++
+
+			if t.MaxMissedHeartbeats > 0 && misses >= t.MaxMissedHeartbeats {
+				fail()
+				return
+			}
+		}
+	}
+}
+
+func (t *Client) runConnection(ctx context.Context, n net.Conn) error {
 	var e error
 	var c *Connection
-	var dc chan bool
+	var wg sync.WaitGroup
 
 	c = NewConnection(n)
-	dc = make(chan bool)
+	done := make(chan struct{})
+
+	// Re-subscribe every live subscription before this connection is handed
+	// out to callers, so a reconnect doesn't silently drop delivery
+	t.subscriptionRegistry.resubscribe(c)
+
+	if t.ConnStatus != nil {
+		select {
+		case t.ConnStatus <- c:
+		default:
+		}
+	}
 
 	// Feed connection until stop
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
+
 		for {
 			select {
 			case t.cc <- c:
-			case <-t.sc:
+			case <-ctx.Done():
 				c.Stop()
 
-				// Wait for c.Run() to return and notify dc
-				<-dc
+				// Wait for c.Run() to return and notify done
+				<-done
 				return
 
-			case <-dc:
+			case <-done:
 				return
 			}
 		}
 	}()
 
 	// Read messages until EOF
+	wg.Add(1)
 	go func() {
+		defer wg.Done()
+
 		var o readObject
 
 		for o = range c.oc {
@@ -309,22 +854,74 @@ func (t *Client) runConnection(n net.Conn) error {
 		}
 	}()
 
+	var hstop chan struct{}
+	var heartbeatFailed int32
+
+	if t.HeartbeatInterval > 0 {
+		hstop = make(chan struct{})
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			t.heartbeat(c, hstop, &heartbeatFailed)
+		}()
+	}
+
 	e = c.Run()
-	dc <- true
+	close(done)
+
+	if hstop != nil {
+		close(hstop)
+	}
+
+	wg.Wait()
+
+	// c.Run() returns nil both when the heartbeat goroutine stopped c after
+	// too many missed PONGs and when c was stopped for a genuine reason
+	// (caller Stop/ctx cancellation). Surface the former as a distinct error
+	// so the return value doesn't read as a deliberate stop.
+	if e == nil && atomic.LoadInt32(&heartbeatFailed) == 1 {
+		e = ErrHeartbeatFailure
+	}
 
 	return e
 }
 
+// Run dials, handshakes and services a connection until it is explicitly
+// stopped via Stop, reconnecting on any connection error in between. It is a
+// thin wrapper around RunContext using context.Background().
 func (t *Client) Run(d Dialer, h Handshaker) error {
+	return t.RunContext(context.Background(), d, h)
+}
+
+// RunContext is the context-aware equivalent of Run: cancelling ctx stops
+// the client the same way calling Stop does.
+func (t *Client) RunContext(ctx context.Context, d Dialer, h Handshaker) error {
 	var n net.Conn
 	var e error
 
-	// There will not be more connections after Run returns
+	ctx, cancel := context.WithCancel(ctx)
+
+	t.runMu.Lock()
+	t.cancel = cancel
+	t.runMu.Unlock()
+
+	defer func() {
+		t.runMu.Lock()
+		t.cancel = nil
+		t.runMu.Unlock()
+
+		cancel()
+	}()
+
+	// There will not be more connections after RunContext returns
 	defer close(t.cc)
 
-	// There will not be more messages after Run returns
+	// There will not be more messages after RunContext returns
 	defer t.subscriptionRegistry.teardown()
 
+	var first = true
+
 	for {
 		n, e = d.Dial()
 		if e != nil {
@@ -338,14 +935,23 @@ func (t *Client) Run(d Dialer, h Handshaker) error {
 			return e
 		}
 
-		e = t.runConnection(n)
-		if e == nil {
-			// No error: client was explicitly stopped
+		if !first {
+			t.recordReconnect()
+		}
+
+		first = false
+
+		e = t.runConnection(ctx, n)
+
+		// runConnection can end for reasons other than ctx being cancelled
+		// (a network error, the server closing the connection, a heartbeat
+		// failure reported as ErrHeartbeatFailure). Its error is not
+		// consulted here: ctx.Err() alone decides that this was a deliberate
+		// stop; anything else reconnects.
+		if ctx.Err() != nil {
 			return nil
 		}
 	}
-
-	return nil
 }
 
 func (t *Client) RunWithDefaults(addr string, user, pass string) error {
@@ -0,0 +1,95 @@
+package nats
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDialer hands out net.Pipe connections and counts how many times Dial
+// is called, so tests can tell a reconnect apart from a clean shutdown. The
+// server side of each pipe is a black hole: it reads and discards whatever
+// arrives and never writes anything back, so PINGs never get a PONG -- the
+// exact scenario the heartbeat feature exists to detect.
+type fakeDialer struct {
+	mu    sync.Mutex
+	dials int
+}
+
+func (d *fakeDialer) Dial() (net.Conn, error) {
+	client, server := net.Pipe()
+
+	d.mu.Lock()
+	d.dials++
+	d.mu.Unlock()
+
+	go func() {
+		var buf [512]byte
+		for {
+			if _, e := server.Read(buf[:]); e != nil {
+				return
+			}
+		}
+	}()
+
+	return client, nil
+}
+
+func (d *fakeDialer) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.dials
+}
+
+// passthroughHandshaker performs no handshake, returning the connection
+// unchanged.
+type passthroughHandshaker struct{}
+
+func (passthroughHandshaker) Handshake(n net.Conn) (net.Conn, error) {
+	return n, nil
+}
+
+// TestRunContextReconnectsAfterHeartbeatFailure verifies that a connection
+// stopped internally by the heartbeat subsystem, rather than by the caller
+// cancelling the run context, causes RunContext to redial instead of
+// shutting the client down for good. This guards against the bug fixed
+// alongside this test, where runConnection's nil return after a
+// heartbeat-forced Stop was indistinguishable from a deliberate stop.
+func TestRunContextReconnectsAfterHeartbeatFailure(t *testing.T) {
+	var d = new(fakeDialer)
+	var c = NewClient()
+
+	c.HeartbeatInterval = 5 * time.Millisecond
+	c.MaxMissedHeartbeats = 2
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- c.RunContext(ctx, d, passthroughHandshaker{})
+	}()
+
+	deadline := time.After(2 * time.Second)
+
+	for d.count() < 2 {
+		select {
+		case e := <-done:
+			t.Fatalf("RunContext returned (%v) after only %d dial(s); want it to reconnect instead of exiting", e, d.count())
+		case <-deadline:
+			t.Fatalf("timed out waiting for a reconnect after a heartbeat failure; dialed %d time(s)", d.count())
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunContext did not return after its context was cancelled")
+	}
+}